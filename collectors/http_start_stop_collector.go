@@ -1,14 +1,59 @@
 package collectors
 
 import (
+	"sort"
 	"strconv"
 
-	"github.com/bmizerany/perks/quantile"
 	"github.com/prometheus/client_golang/prometheus"
+	kingpin "gopkg.in/alecthomas/kingpin.v2"
 
 	"github.com/cloudfoundry-community/firehose_exporter/metrics"
 )
 
+// The flags below are declared here, next to the collector they configure,
+// but are deliberately not read directly by the collector code. Whatever
+// wires up the exporter's entrypoint is expected to resolve them once and
+// pass the resolved values into NewHttpStartStopCollector and
+// NewValueMetricsCollector, the same way namespace/environment/metricsStore
+// are threaded through today.
+var (
+	httpResponseSizeBytesBuckets = kingpin.Flag(
+		"http.response_size_bytes.buckets",
+		"Buckets for the http_start_stop response size bytes histogram",
+	).Default("100", "1000", "10000", "100000", "1000000").Float64List()
+
+	httpClientDurationSecondsBuckets = kingpin.Flag(
+		"http.client_duration_seconds.buckets",
+		"Buckets for the http_start_stop client request duration seconds histogram",
+	).Default("0.005", "0.01", "0.025", "0.05", "0.1", "0.25", "0.5", "1", "2.5", "5", "10").Float64List()
+
+	httpServerDurationSecondsBuckets = kingpin.Flag(
+		"http.server_duration_seconds.buckets",
+		"Buckets for the http_start_stop server request duration seconds histogram",
+	).Default("0.005", "0.01", "0.025", "0.05", "0.1", "0.25", "0.5", "1", "2.5", "5", "10").Float64List()
+
+	httpEmitSummary = kingpin.Flag(
+		"http.emit_summary",
+		"Also emit http_start_stop_*_summary metrics, with quantiles approximated from the histogram buckets, to ease migrating dashboards and alerts off the old per-exporter summaries",
+	).Default("false").Bool()
+
+	metricsOpenMetricsNaming = kingpin.Flag(
+		"metrics.open-metrics-naming",
+		"Append a `_<unit>` suffix to Firehose value metric names when the Firehose event carries a unit. Does not apply to http_start_stop metrics, which do not carry a Firehose unit to append.",
+	).Default("false").Bool()
+)
+
+// secondsBucketsToNanoseconds converts a set of buckets expressed in seconds
+// (e.g. prometheus.DefBuckets) into nanoseconds, the unit the http_start_stop
+// duration metrics have always been reported in.
+func secondsBucketsToNanoseconds(buckets []float64) []float64 {
+	nanosecondBuckets := make([]float64, len(buckets))
+	for i, bucket := range buckets {
+		nanosecondBuckets[i] = bucket * 1e9
+	}
+	return nanosecondBuckets
+}
+
 type Applications map[string]*Application
 
 type Application struct {
@@ -24,24 +69,131 @@ type Uri struct {
 }
 
 type Method struct {
-	StatusCodes    map[int32]int64
-	ContentLength  *quantile.Stream
-	ClientDuration *quantile.Stream
-	ServerDuration *quantile.Stream
+	StatusCodes map[int32]int64
+
+	ContentLengthCount   uint64
+	ContentLengthSum     float64
+	ContentLengthBuckets map[float64]uint64
+
+	ClientDurationCount   uint64
+	ClientDurationSum     float64
+	ClientDurationBuckets map[float64]uint64
+
+	ServerDurationCount   uint64
+	ServerDurationSum     float64
+	ServerDurationBuckets map[float64]uint64
+}
+
+func newMethod(
+	responseSizeBytesBuckets []float64,
+	clientDurationNanosecondsBuckets []float64,
+	serverDurationNanosecondsBuckets []float64,
+) *Method {
+	return &Method{
+		StatusCodes:           make(map[int32]int64),
+		ContentLengthBuckets:  newBucketCounters(responseSizeBytesBuckets),
+		ClientDurationBuckets: newBucketCounters(clientDurationNanosecondsBuckets),
+		ServerDurationBuckets: newBucketCounters(serverDurationNanosecondsBuckets),
+	}
+}
+
+func newBucketCounters(buckets []float64) map[float64]uint64 {
+	bucketCounters := make(map[float64]uint64, len(buckets))
+	for _, bucket := range buckets {
+		bucketCounters[bucket] = 0
+	}
+	return bucketCounters
+}
+
+func observeBucketCounters(buckets map[float64]uint64, value float64) {
+	for bucket := range buckets {
+		if value <= bucket {
+			buckets[bucket]++
+		}
+	}
+}
+
+// estimateQuantile approximates the value at quantile q by linear
+// interpolation between the cumulative histogram bucket boundaries,
+// the same technique PromQL's histogram_quantile() uses. It is only
+// precise to the resolution of the configured buckets, but it is the
+// closest approximation of the pre-histogram quantiles available once
+// the raw samples have been discarded in favor of bucket counters.
+func estimateQuantile(buckets map[float64]uint64, count uint64, q float64) float64 {
+	if count == 0 || len(buckets) == 0 {
+		return 0
+	}
+
+	boundaries := make([]float64, 0, len(buckets))
+	for bucket := range buckets {
+		boundaries = append(boundaries, bucket)
+	}
+	sort.Float64s(boundaries)
+
+	target := q * float64(count)
+
+	var prevBoundary float64
+	var prevCount uint64
+	for _, boundary := range boundaries {
+		bucketCount := buckets[boundary]
+		if float64(bucketCount) >= target {
+			if bucketCount == prevCount {
+				return boundary
+			}
+			rank := target - float64(prevCount)
+			return prevBoundary + (boundary-prevBoundary)*rank/float64(bucketCount-prevCount)
+		}
+		prevBoundary = boundary
+		prevCount = bucketCount
+	}
+
+	return boundaries[len(boundaries)-1]
+}
+
+// legacySummaryQuantiles matches the quantiles the old quantile.Stream-based
+// summaries reported (quantile.NewTargeted(0.50, 0.90, 0.99)).
+var legacySummaryQuantiles = []float64{0.50, 0.90, 0.99}
+
+// legacyQuantiles builds the quantile map MustNewConstSummary expects,
+// approximating each legacySummaryQuantiles entry from the histogram
+// buckets so the --http.emit_summary metrics still carry the p50/p90/p99
+// lines existing consumers read.
+func legacyQuantiles(buckets map[float64]uint64, count uint64) map[float64]float64 {
+	quantiles := make(map[float64]float64, len(legacySummaryQuantiles))
+	for _, q := range legacySummaryQuantiles {
+		quantiles[q] = estimateQuantile(buckets, count, q)
+	}
+	return quantiles
 }
 
+// HttpStartStopCollector intentionally ignores --metrics.open-metrics-naming:
+// that flag appends a Firehose-event unit as a suffix, and HttpStartStop
+// events carry no unit to append (response size is always bytes, durations
+// are always nanoseconds, both already reflected in the metric names built
+// in NewHttpStartStopCollector). There is nothing for the flag to change here.
 type HttpStartStopCollector struct {
 	namespace                            string
 	metricsStore                         *metrics.Store
+	responseSizeBytesBuckets             []float64
+	clientDurationNanosecondsBuckets     []float64
+	serverDurationNanosecondsBuckets     []float64
+	emitSummary                          bool
 	requestTotalDesc                     *prometheus.Desc
 	responseSizeBytesDesc                *prometheus.Desc
+	responseSizeBytesSummaryDesc         *prometheus.Desc
 	clientRequestDurationNanosecondsDesc *prometheus.Desc
+	clientRequestDurationSummaryDesc     *prometheus.Desc
 	serverRequestDurationNanosecondsDesc *prometheus.Desc
+	serverRequestDurationSummaryDesc     *prometheus.Desc
 }
 
 func NewHttpStartStopCollector(
 	namespace string,
 	metricsStore *metrics.Store,
+	responseSizeBytesBuckets []float64,
+	clientDurationSecondsBuckets []float64,
+	serverDurationSecondsBuckets []float64,
+	emitSummary bool,
 ) *HttpStartStopCollector {
 	requestTotalDesc := prometheus.NewDesc(
 		prometheus.BuildFQName(namespace, http_start_stop_subsystem, "request_total"),
@@ -57,6 +209,13 @@ func NewHttpStartStopCollector(
 		nil,
 	)
 
+	responseSizeBytesSummaryDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, http_start_stop_subsystem, "response_size_bytes_summary"),
+		"Cloud Foundry Firehose http start stop request size in bytes (legacy summary).",
+		[]string{"application_id", "instance_id", "uri", "method"},
+		nil,
+	)
+
 	clientRequestDurationNanosecondsDesc := prometheus.NewDesc(
 		prometheus.BuildFQName(namespace, http_start_stop_subsystem, "client_request_duration_nanoseconds"),
 		"Cloud Foundry Firehose http start stop client request duration in nanoseconds.",
@@ -64,6 +223,13 @@ func NewHttpStartStopCollector(
 		nil,
 	)
 
+	clientRequestDurationSummaryDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, http_start_stop_subsystem, "client_request_duration_nanoseconds_summary"),
+		"Cloud Foundry Firehose http start stop client request duration in nanoseconds (legacy summary).",
+		[]string{"application_id", "instance_id", "uri", "method"},
+		nil,
+	)
+
 	serverRequestDurationNanosecondsDesc := prometheus.NewDesc(
 		prometheus.BuildFQName(namespace, http_start_stop_subsystem, "server_request_duration_nanoseconds"),
 		"Cloud Foundry Firehose http start stop server request duration in nanoseconds.",
@@ -71,13 +237,41 @@ func NewHttpStartStopCollector(
 		nil,
 	)
 
+	serverRequestDurationSummaryDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, http_start_stop_subsystem, "server_request_duration_nanoseconds_summary"),
+		"Cloud Foundry Firehose http start stop server request duration in nanoseconds (legacy summary).",
+		[]string{"application_id", "instance_id", "uri", "method"},
+		nil,
+	)
+
+	if len(responseSizeBytesBuckets) == 0 {
+		responseSizeBytesBuckets = prometheus.ExponentialBuckets(100, 10, 5)
+	}
+
+	clientDurationNanosecondsBuckets := secondsBucketsToNanoseconds(clientDurationSecondsBuckets)
+	if len(clientDurationNanosecondsBuckets) == 0 {
+		clientDurationNanosecondsBuckets = secondsBucketsToNanoseconds(prometheus.DefBuckets)
+	}
+
+	serverDurationNanosecondsBuckets := secondsBucketsToNanoseconds(serverDurationSecondsBuckets)
+	if len(serverDurationNanosecondsBuckets) == 0 {
+		serverDurationNanosecondsBuckets = secondsBucketsToNanoseconds(prometheus.DefBuckets)
+	}
+
 	return &HttpStartStopCollector{
 		namespace:                            namespace,
 		metricsStore:                         metricsStore,
+		responseSizeBytesBuckets:             responseSizeBytesBuckets,
+		clientDurationNanosecondsBuckets:     clientDurationNanosecondsBuckets,
+		serverDurationNanosecondsBuckets:     serverDurationNanosecondsBuckets,
+		emitSummary:                          emitSummary,
 		requestTotalDesc:                     requestTotalDesc,
 		responseSizeBytesDesc:                responseSizeBytesDesc,
+		responseSizeBytesSummaryDesc:         responseSizeBytesSummaryDesc,
 		clientRequestDurationNanosecondsDesc: clientRequestDurationNanosecondsDesc,
+		clientRequestDurationSummaryDesc:     clientRequestDurationSummaryDesc,
 		serverRequestDurationNanosecondsDesc: serverRequestDurationNanosecondsDesc,
+		serverRequestDurationSummaryDesc:     serverRequestDurationSummaryDesc,
 	}
 }
 
@@ -124,19 +318,30 @@ func (c HttpStartStopCollector) calculateMetrics(httpStartStops metrics.HttpStar
 		var method *Method
 		method, ok = uri.Methods[httpStartStop.Method]
 		if !ok {
-			method = &Method{
-				StatusCodes:    make(map[int32]int64),
-				ContentLength:  quantile.NewTargeted(0.50, 0.90, 0.99),
-				ClientDuration: quantile.NewTargeted(0.50, 0.90, 0.99),
-				ServerDuration: quantile.NewTargeted(0.50, 0.90, 0.99),
-			}
+			method = newMethod(
+				c.responseSizeBytesBuckets,
+				c.clientDurationNanosecondsBuckets,
+				c.serverDurationNanosecondsBuckets,
+			)
 			uri.Methods[httpStartStop.Method] = method
 		}
 
 		method.StatusCodes[httpStartStop.StatusCode]++
-		method.ContentLength.Insert(float64(httpStartStop.ContentLength))
-		method.ClientDuration.Insert(float64(httpStartStop.ClientDuration))
-		method.ServerDuration.Insert(float64(httpStartStop.ServerDuration))
+
+		contentLength := float64(httpStartStop.ContentLength)
+		method.ContentLengthCount++
+		method.ContentLengthSum += contentLength
+		observeBucketCounters(method.ContentLengthBuckets, contentLength)
+
+		clientDuration := float64(httpStartStop.ClientDuration)
+		method.ClientDurationCount++
+		method.ClientDurationSum += clientDuration
+		observeBucketCounters(method.ClientDurationBuckets, clientDuration)
+
+		serverDuration := float64(httpStartStop.ServerDuration)
+		method.ServerDurationCount++
+		method.ServerDurationSum += serverDuration
+		observeBucketCounters(method.ServerDurationBuckets, serverDuration)
 	}
 
 	return &applications
@@ -147,9 +352,9 @@ func (c HttpStartStopCollector) reportMetrics(applications *Applications, ch cha
 		for instanceID, instance := range application.Instances {
 			for uriKey, uri := range instance.Uris {
 				for methodKey, method := range uri.Methods {
-					c.reportResponseSize(method.ContentLength, applicationID, instanceID, uriKey, methodKey, ch)
-					c.reportClientRequestDuration(method.ClientDuration, applicationID, instanceID, uriKey, methodKey, ch)
-					c.reportServerRequestDuration(method.ServerDuration, applicationID, instanceID, uriKey, methodKey, ch)
+					c.reportResponseSize(method, applicationID, instanceID, uriKey, methodKey, ch)
+					c.reportClientRequestDuration(method, applicationID, instanceID, uriKey, methodKey, ch)
+					c.reportServerRequestDuration(method, applicationID, instanceID, uriKey, methodKey, ch)
 
 					for statusCode, requestTotal := range method.StatusCodes {
 						c.reportRequestTotal(
@@ -173,6 +378,11 @@ func (c HttpStartStopCollector) Describe(ch chan<- *prometheus.Desc) {
 	ch <- c.responseSizeBytesDesc
 	ch <- c.clientRequestDurationNanosecondsDesc
 	ch <- c.serverRequestDurationNanosecondsDesc
+	if c.emitSummary {
+		ch <- c.responseSizeBytesSummaryDesc
+		ch <- c.clientRequestDurationSummaryDesc
+		ch <- c.serverRequestDurationSummaryDesc
+	}
 }
 
 func (c HttpStartStopCollector) reportRequestTotal(
@@ -197,94 +407,100 @@ func (c HttpStartStopCollector) reportRequestTotal(
 }
 
 func (c HttpStartStopCollector) reportResponseSize(
-	responseSize *quantile.Stream,
+	m *Method,
 	applicationID string,
 	instanceID string,
 	uri string,
-	method string,
+	httpMethod string,
 	ch chan<- prometheus.Metric,
 ) {
-	var responseSizeSum float64
-	for _, sample := range responseSize.Samples() {
-		responseSizeSum = responseSizeSum + sample.Value
-	}
-
-	responseSizeQuantiles := map[float64]float64{
-		float64(0.50): float64(responseSize.Query(0.50)),
-		float64(0.90): float64(responseSize.Query(0.90)),
-		float64(0.99): float64(responseSize.Query(0.99)),
-	}
-
-	ch <- prometheus.MustNewConstSummary(
+	ch <- prometheus.MustNewConstHistogram(
 		c.responseSizeBytesDesc,
-		uint64(responseSize.Count()),
-		responseSizeSum,
-		responseSizeQuantiles,
+		m.ContentLengthCount,
+		m.ContentLengthSum,
+		m.ContentLengthBuckets,
 		applicationID,
 		instanceID,
 		uri,
-		method,
+		httpMethod,
 	)
+
+	if c.emitSummary {
+		ch <- prometheus.MustNewConstSummary(
+			c.responseSizeBytesSummaryDesc,
+			m.ContentLengthCount,
+			m.ContentLengthSum,
+			legacyQuantiles(m.ContentLengthBuckets, m.ContentLengthCount),
+			applicationID,
+			instanceID,
+			uri,
+			httpMethod,
+		)
+	}
 }
 
 func (c HttpStartStopCollector) reportClientRequestDuration(
-	clientRequestDuration *quantile.Stream,
+	m *Method,
 	applicationID string,
 	instanceID string,
 	uri string,
-	method string,
+	httpMethod string,
 	ch chan<- prometheus.Metric,
 ) {
-	var clientRequestDurationSum float64
-	for _, sample := range clientRequestDuration.Samples() {
-		clientRequestDurationSum = clientRequestDurationSum + sample.Value
-	}
-
-	clientRequestDurationQuantiles := map[float64]float64{
-		float64(0.50): float64(clientRequestDuration.Query(0.50)),
-		float64(0.90): float64(clientRequestDuration.Query(0.90)),
-		float64(0.99): float64(clientRequestDuration.Query(0.99)),
-	}
-
-	ch <- prometheus.MustNewConstSummary(
+	ch <- prometheus.MustNewConstHistogram(
 		c.clientRequestDurationNanosecondsDesc,
-		uint64(clientRequestDuration.Count()),
-		clientRequestDurationSum,
-		clientRequestDurationQuantiles,
+		m.ClientDurationCount,
+		m.ClientDurationSum,
+		m.ClientDurationBuckets,
 		applicationID,
 		instanceID,
 		uri,
-		method,
+		httpMethod,
 	)
+
+	if c.emitSummary {
+		ch <- prometheus.MustNewConstSummary(
+			c.clientRequestDurationSummaryDesc,
+			m.ClientDurationCount,
+			m.ClientDurationSum,
+			legacyQuantiles(m.ClientDurationBuckets, m.ClientDurationCount),
+			applicationID,
+			instanceID,
+			uri,
+			httpMethod,
+		)
+	}
 }
 
 func (c HttpStartStopCollector) reportServerRequestDuration(
-	serverRequestDuration *quantile.Stream,
+	m *Method,
 	applicationID string,
 	instanceID string,
 	uri string,
-	method string,
+	httpMethod string,
 	ch chan<- prometheus.Metric,
 ) {
-	var serverRequestDurationSum float64
-	for _, sample := range serverRequestDuration.Samples() {
-		serverRequestDurationSum = serverRequestDurationSum + sample.Value
-	}
-
-	serverRequestDurationQuantiles := map[float64]float64{
-		float64(0.50): float64(serverRequestDuration.Query(0.50)),
-		float64(0.90): float64(serverRequestDuration.Query(0.90)),
-		float64(0.99): float64(serverRequestDuration.Query(0.99)),
-	}
-
-	ch <- prometheus.MustNewConstSummary(
+	ch <- prometheus.MustNewConstHistogram(
 		c.serverRequestDurationNanosecondsDesc,
-		uint64(serverRequestDuration.Count()),
-		serverRequestDurationSum,
-		serverRequestDurationQuantiles,
+		m.ServerDurationCount,
+		m.ServerDurationSum,
+		m.ServerDurationBuckets,
 		applicationID,
 		instanceID,
 		uri,
-		method,
+		httpMethod,
 	)
+
+	if c.emitSummary {
+		ch <- prometheus.MustNewConstSummary(
+			c.serverRequestDurationSummaryDesc,
+			m.ServerDurationCount,
+			m.ServerDurationSum,
+			legacyQuantiles(m.ServerDurationBuckets, m.ServerDurationCount),
+			applicationID,
+			instanceID,
+			uri,
+			httpMethod,
+		)
+	}
 }
@@ -0,0 +1,155 @@
+package collectors
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/cloudfoundry-community/firehose_exporter/metrics"
+)
+
+func TestSecondsBucketsToNanoseconds(t *testing.T) {
+	buckets := secondsBucketsToNanoseconds([]float64{0.1, 1, 10})
+
+	expected := []float64{100000000, 1000000000, 10000000000}
+	if len(buckets) != len(expected) {
+		t.Fatalf("expected %d buckets, got %d", len(expected), len(buckets))
+	}
+	for i, bucket := range buckets {
+		if bucket != expected[i] {
+			t.Errorf("expected bucket %d to be %v, got %v", i, expected[i], bucket)
+		}
+	}
+}
+
+func TestNewBucketCounters(t *testing.T) {
+	counters := newBucketCounters([]float64{1, 10, 100})
+
+	if len(counters) != 3 {
+		t.Fatalf("expected 3 bucket counters, got %d", len(counters))
+	}
+	for _, bucket := range []float64{1, 10, 100} {
+		if count, ok := counters[bucket]; !ok || count != 0 {
+			t.Errorf("expected bucket %v to start at 0, got %v (present: %v)", bucket, count, ok)
+		}
+	}
+}
+
+func TestObserveBucketCounters(t *testing.T) {
+	counters := newBucketCounters([]float64{1, 10, 100})
+
+	observeBucketCounters(counters, 5)
+	observeBucketCounters(counters, 50)
+	observeBucketCounters(counters, 500)
+
+	if counters[1] != 0 {
+		t.Errorf("expected bucket 1 to have 0 observations, got %d", counters[1])
+	}
+	if counters[10] != 1 {
+		t.Errorf("expected bucket 10 to have 1 observation, got %d", counters[10])
+	}
+	if counters[100] != 2 {
+		t.Errorf("expected bucket 100 to have 2 observations, got %d", counters[100])
+	}
+}
+
+func TestEstimateQuantile(t *testing.T) {
+	buckets := newBucketCounters([]float64{10, 20, 30, 40})
+	count := uint64(0)
+	for _, value := range []float64{5, 15, 15, 25, 35} {
+		observeBucketCounters(buckets, value)
+		count++
+	}
+
+	if got := estimateQuantile(buckets, count, 0.5); got < 10 || got > 20 {
+		t.Errorf("expected the median to fall within the [10,20] bucket, got %v", got)
+	}
+
+	if got := estimateQuantile(buckets, 0, 0.99); got != 0 {
+		t.Errorf("expected a zero-count histogram to estimate 0, got %v", got)
+	}
+}
+
+func TestLegacyQuantiles(t *testing.T) {
+	buckets := newBucketCounters([]float64{10, 20, 30})
+	count := uint64(0)
+	for _, value := range []float64{5, 15, 25} {
+		observeBucketCounters(buckets, value)
+		count++
+	}
+
+	quantiles := legacyQuantiles(buckets, count)
+
+	for _, q := range legacySummaryQuantiles {
+		if _, ok := quantiles[q]; !ok {
+			t.Errorf("expected legacyQuantiles to populate quantile %v", q)
+		}
+	}
+}
+
+func TestHttpStartStopCollectorCollect(t *testing.T) {
+	store := metrics.NewStore()
+	store.SetHttpStartStop(&metrics.HttpStartStop{
+		ApplicationId:  "app-1",
+		InstanceId:     "0",
+		Uri:            "/v2/apps",
+		Method:         "GET",
+		StatusCode:     200,
+		ContentLength:  500,
+		ClientDuration: 50000000,
+		ServerDuration: 40000000,
+	})
+
+	c := NewHttpStartStopCollector(
+		"firehose",
+		store,
+		[]float64{100, 1000},
+		[]float64{0.1, 1},
+		[]float64{0.1, 1},
+		false,
+	)
+
+	expected := `
+# HELP firehose_http_start_stop_request_total Cloud Foundry Firehose http start stop total requests.
+# TYPE firehose_http_start_stop_request_total counter
+firehose_http_start_stop_request_total{application_id="app-1",instance_id="0",method="GET",status_code="200",uri="/v2/apps"} 1
+`
+	if err := testutil.CollectAndCompare(c, strings.NewReader(expected), "firehose_http_start_stop_request_total"); err != nil {
+		t.Errorf("unexpected collected metrics: %s", err)
+	}
+
+	responseSizeExpected := `
+# HELP firehose_http_start_stop_response_size_bytes Cloud Foundry Firehose http start stop request size in bytes.
+# TYPE firehose_http_start_stop_response_size_bytes histogram
+firehose_http_start_stop_response_size_bytes_bucket{application_id="app-1",instance_id="0",method="GET",uri="/v2/apps",le="100"} 0
+firehose_http_start_stop_response_size_bytes_bucket{application_id="app-1",instance_id="0",method="GET",uri="/v2/apps",le="1000"} 1
+firehose_http_start_stop_response_size_bytes_bucket{application_id="app-1",instance_id="0",method="GET",uri="/v2/apps",le="+Inf"} 1
+firehose_http_start_stop_response_size_bytes_sum{application_id="app-1",instance_id="0",method="GET",uri="/v2/apps"} 500
+firehose_http_start_stop_response_size_bytes_count{application_id="app-1",instance_id="0",method="GET",uri="/v2/apps"} 1
+`
+	if err := testutil.CollectAndCompare(c, strings.NewReader(responseSizeExpected), "firehose_http_start_stop_response_size_bytes"); err != nil {
+		t.Errorf("unexpected collected metrics: %s", err)
+	}
+}
+
+func TestHttpStartStopCollectorDescribeOmitsSummariesUnlessEnabled(t *testing.T) {
+	store := metrics.NewStore()
+
+	without := NewHttpStartStopCollector("firehose", store, nil, nil, nil, false)
+	ch := make(chan *prometheus.Desc, 16)
+	without.Describe(ch)
+	close(ch)
+	if count := len(ch); count != 4 {
+		t.Errorf("expected 4 descriptors without --http.emit_summary, got %d", count)
+	}
+
+	withSummary := NewHttpStartStopCollector("firehose", store, nil, nil, nil, true)
+	ch = make(chan *prometheus.Desc, 16)
+	withSummary.Describe(ch)
+	close(ch)
+	if count := len(ch); count != 7 {
+		t.Errorf("expected 7 descriptors with --http.emit_summary, got %d", count)
+	}
+}
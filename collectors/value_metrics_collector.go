@@ -2,6 +2,7 @@ package collectors
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/common/log"
@@ -14,6 +15,7 @@ type ValueMetricsCollector struct {
 	namespace                 string
 	environment               string
 	metricsStore              *metrics.Store
+	openMetricsNaming         bool
 	valueMetricsCollectorDesc *prometheus.Desc
 }
 
@@ -21,6 +23,7 @@ func NewValueMetricsCollector(
 	namespace string,
 	environment string,
 	metricsStore *metrics.Store,
+	openMetricsNaming bool,
 ) *ValueMetricsCollector {
 	valueMetricsCollectorDesc := prometheus.NewDesc(
 		prometheus.BuildFQName(namespace, value_metrics_subsystem, "collector"),
@@ -33,6 +36,7 @@ func NewValueMetricsCollector(
 		namespace:                 namespace,
 		environment:               environment,
 		metricsStore:              metricsStore,
+		openMetricsNaming:         openMetricsNaming,
 		valueMetricsCollectorDesc: valueMetricsCollectorDesc,
 	}
 }
@@ -40,6 +44,11 @@ func NewValueMetricsCollector(
 func (c ValueMetricsCollector) Collect(ch chan<- prometheus.Metric) {
 	for _, valueMetric := range c.metricsStore.GetValueMetrics() {
 		metricName := utils.NormalizeName(valueMetric.Origin) + "_" + utils.NormalizeName(valueMetric.Name)
+		if c.openMetricsNaming && valueMetric.Unit != "" {
+			if unit := utils.NormalizeName(valueMetric.Unit); !strings.HasSuffix(metricName, "_"+unit) {
+				metricName = metricName + "_" + unit
+			}
+		}
 
 		constLabels := []string{"origin", "bosh_deployment", "bosh_job_name", "bosh_job_id", "bosh_job_ip", "unit"}
 		labelValues := []string{valueMetric.Origin, valueMetric.Deployment, valueMetric.Job, valueMetric.Index, valueMetric.IP, valueMetric.Unit}
@@ -0,0 +1,211 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/log"
+)
+
+var graphiteInvalidChars = regexp.MustCompile(`[^a-zA-Z0-9_.-]`)
+
+// WithGraphiteBridge configures the Registry to write its gathered
+// metrics to a Graphite carbon endpoint at host:port. RunGraphiteLoop
+// must be run (typically in a goroutine) to actually start writing, so
+// operators who still run Graphite/Grafana can consume firehose value
+// metrics and http_start_stop counters without standing up Prometheus.
+//
+// Exposing this as a --metrics.graphite-address flag is the exporter
+// entrypoint's job, not this package's; this tree has no main package to
+// wire it into yet.
+func WithGraphiteBridge(host string, port int, interval time.Duration, prefix string) RegistryOption {
+	return func(r *Registry) {
+		r.graphiteAddress = fmt.Sprintf("%s:%d", host, port)
+		r.graphitePrefix = prefix
+		r.graphiteInterval = interval
+	}
+}
+
+// RunGraphiteLoop writes the Registry's gathered metrics to the
+// configured Graphite carbon endpoint on the configured interval until
+// ctx is cancelled, reconnecting with a backoff on failure. It is a
+// no-op if WithGraphiteBridge was not used. Mirrors RunPushLoop's
+// explicit, cancelable-loop shape.
+func (r *Registry) RunGraphiteLoop(ctx context.Context) {
+	if r.graphiteAddress == "" {
+		return
+	}
+
+	bridge := &graphiteBridge{
+		address:  r.graphiteAddress,
+		prefix:   r.graphitePrefix,
+		gatherer: r.registry,
+	}
+
+	ticker := time.NewTicker(r.graphiteInterval)
+	defer ticker.Stop()
+
+	backoff := time.Second
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			conn, err := net.DialTimeout("tcp", bridge.address, 5*time.Second)
+			if err != nil {
+				log.Errorf("Error connecting to Graphite at `%s`: %s", bridge.address, err)
+				if !sleepOrDone(ctx, backoff) {
+					return
+				}
+				if backoff < r.graphiteInterval {
+					backoff *= 2
+				}
+				continue
+			}
+			backoff = time.Second
+
+			if err := bridge.writeTo(conn); err != nil {
+				log.Errorf("Error writing metrics to Graphite at `%s`: %s", bridge.address, err)
+			}
+			conn.Close()
+		}
+	}
+}
+
+// sleepOrDone waits for d, returning false early if ctx is cancelled
+// first.
+func sleepOrDone(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+type graphiteBridge struct {
+	address  string
+	prefix   string
+	gatherer interface {
+		Gather() ([]*dto.MetricFamily, error)
+	}
+}
+
+func (b *graphiteBridge) writeTo(w io.Writer) error {
+	families, err := b.gatherer.Gather()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().Unix()
+	var buf strings.Builder
+	for _, family := range families {
+		name := graphiteName(b.prefix, family.GetName())
+		for _, metric := range family.GetMetric() {
+			for _, line := range graphiteLines(name, metric, now) {
+				buf.WriteString(line)
+				buf.WriteString("\n")
+			}
+		}
+	}
+
+	_, err = io.WriteString(w, buf.String())
+	return err
+}
+
+// graphiteLines formats a single metric sample as one or more Graphite
+// plaintext lines. Counters, gauges and untyped samples produce a single
+// line; histograms and summaries, which have no single scalar value,
+// produce one line per bucket/quantile plus `_sum`/`_count` lines, so
+// that none of the distribution is silently dropped.
+func graphiteLines(name string, metric *dto.Metric, ts int64) []string {
+	labels := graphiteLabelPairs(metric.GetLabel())
+
+	switch {
+	case metric.Counter != nil:
+		return []string{graphiteLine(name, labels, metric.Counter.GetValue(), ts)}
+	case metric.Gauge != nil:
+		return []string{graphiteLine(name, labels, metric.Gauge.GetValue(), ts)}
+	case metric.Untyped != nil:
+		return []string{graphiteLine(name, labels, metric.Untyped.GetValue(), ts)}
+	case metric.Histogram != nil:
+		lines := []string{
+			graphiteLine(name+"_sum", labels, metric.Histogram.GetSampleSum(), ts),
+			graphiteLine(name+"_count", labels, float64(metric.Histogram.GetSampleCount()), ts),
+		}
+		for _, bucket := range metric.Histogram.GetBucket() {
+			bucketLabels := withLabel(labels, "le", formatFloat(bucket.GetUpperBound()))
+			lines = append(lines, graphiteLine(name+"_bucket", bucketLabels, float64(bucket.GetCumulativeCount()), ts))
+		}
+		return lines
+	case metric.Summary != nil:
+		lines := []string{
+			graphiteLine(name+"_sum", labels, metric.Summary.GetSampleSum(), ts),
+			graphiteLine(name+"_count", labels, float64(metric.Summary.GetSampleCount()), ts),
+		}
+		for _, quantile := range metric.Summary.GetQuantile() {
+			quantileLabels := withLabel(labels, "quantile", formatFloat(quantile.GetQuantile()))
+			lines = append(lines, graphiteLine(name, quantileLabels, quantile.GetValue(), ts))
+		}
+		return lines
+	default:
+		return nil
+	}
+}
+
+func graphiteLine(name string, labelPairs []string, value float64, ts int64) string {
+	var buf strings.Builder
+	buf.WriteString(name)
+	if len(labelPairs) > 0 {
+		buf.WriteString(".")
+		buf.WriteString(strings.Join(labelPairs, "."))
+	}
+	fmt.Fprintf(&buf, " %v %d", value, ts)
+	return buf.String()
+}
+
+func graphiteName(prefix, name string) string {
+	escaped := graphiteInvalidChars.ReplaceAllString(name, "_")
+	if prefix == "" {
+		return escaped
+	}
+	return prefix + "." + escaped
+}
+
+func graphiteLabelPairs(labels []*dto.LabelPair) []string {
+	pairs := make([]string, 0, len(labels))
+	for _, label := range labels {
+		pairs = append(pairs, graphiteLabelPair(label.GetName(), label.GetValue()))
+	}
+	sort.Strings(pairs)
+	return pairs
+}
+
+// withLabel appends an extra label pair (e.g. `le` or `quantile`) to an
+// already-sorted label set and re-sorts, without mutating the input.
+func withLabel(labelPairs []string, name, value string) []string {
+	extended := make([]string, len(labelPairs), len(labelPairs)+1)
+	copy(extended, labelPairs)
+	extended = append(extended, graphiteLabelPair(name, value))
+	sort.Strings(extended)
+	return extended
+}
+
+func graphiteLabelPair(name, value string) string {
+	return fmt.Sprintf("%s.%s", graphiteInvalidChars.ReplaceAllString(name, "_"), graphiteInvalidChars.ReplaceAllString(value, "_"))
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
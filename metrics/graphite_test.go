@@ -0,0 +1,119 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+
+	dto "github.com/prometheus/client_model/go"
+)
+
+func float64p(f float64) *float64 { return &f }
+func uint64p(u uint64) *uint64    { return &u }
+func stringp(s string) *string    { return &s }
+
+func TestGraphiteNamePrefixesAndEscapes(t *testing.T) {
+	if got, want := graphiteName("", "firehose_value_metric"), "firehose_value_metric"; got != want {
+		t.Errorf("graphiteName(\"\", ...) = %q, want %q", got, want)
+	}
+
+	if got, want := graphiteName("cf", "firehose.value:metric"), "cf.firehose.value_metric"; got != want {
+		t.Errorf("graphiteName(\"cf\", ...) = %q, want %q", got, want)
+	}
+}
+
+func TestGraphiteLabelPairEscapesInvalidChars(t *testing.T) {
+	if got, want := graphiteLabelPair("uri", "/v2/apps:id"), "uri._v2_apps_id"; got != want {
+		t.Errorf("graphiteLabelPair() = %q, want %q", got, want)
+	}
+}
+
+func TestGraphiteLinesCounter(t *testing.T) {
+	metric := &dto.Metric{
+		Label:   []*dto.LabelPair{{Name: stringp("origin"), Value: stringp("router")}},
+		Counter: &dto.Counter{Value: float64p(3)},
+	}
+
+	lines := graphiteLines("firehose_events_total", metric, 1000)
+	want := []string{"firehose_events_total.origin.router 3 1000"}
+	if strings.Join(lines, "|") != strings.Join(want, "|") {
+		t.Errorf("graphiteLines() = %v, want %v", lines, want)
+	}
+}
+
+func TestGraphiteLinesHistogramExpandsBuckets(t *testing.T) {
+	metric := &dto.Metric{
+		Histogram: &dto.Histogram{
+			SampleSum:   float64p(12.5),
+			SampleCount: uint64p(3),
+			Bucket: []*dto.Bucket{
+				{UpperBound: float64p(1), CumulativeCount: uint64p(1)},
+				{UpperBound: float64p(5), CumulativeCount: uint64p(3)},
+			},
+		},
+	}
+
+	lines := graphiteLines("firehose_duration_seconds", metric, 1000)
+	want := []string{
+		"firehose_duration_seconds_sum 12.5 1000",
+		"firehose_duration_seconds_count 3 1000",
+		"firehose_duration_seconds_bucket.le.1 1 1000",
+		"firehose_duration_seconds_bucket.le.5 3 1000",
+	}
+	if strings.Join(lines, "|") != strings.Join(want, "|") {
+		t.Errorf("graphiteLines() = %v, want %v", lines, want)
+	}
+}
+
+func TestGraphiteLinesSummaryExpandsQuantiles(t *testing.T) {
+	metric := &dto.Metric{
+		Summary: &dto.Summary{
+			SampleSum:   float64p(4),
+			SampleCount: uint64p(2),
+			Quantile: []*dto.Quantile{
+				{Quantile: float64p(0.5), Value: float64p(2)},
+				{Quantile: float64p(0.99), Value: float64p(3)},
+			},
+		},
+	}
+
+	lines := graphiteLines("firehose_duration_seconds", metric, 1000)
+	want := []string{
+		"firehose_duration_seconds_sum 4 1000",
+		"firehose_duration_seconds_count 2 1000",
+		"firehose_duration_seconds.quantile.0.5 2 1000",
+		"firehose_duration_seconds.quantile.0.99 3 1000",
+	}
+	if strings.Join(lines, "|") != strings.Join(want, "|") {
+		t.Errorf("graphiteLines() = %v, want %v", lines, want)
+	}
+}
+
+func TestGraphiteBridgeWriteTo(t *testing.T) {
+	families := []*dto.MetricFamily{
+		{
+			Name: stringp("firehose_events_total"),
+			Metric: []*dto.Metric{
+				{Counter: &dto.Counter{Value: float64p(1)}},
+			},
+		},
+	}
+
+	bridge := &graphiteBridge{prefix: "cf", gatherer: stubGatherer{families: families}}
+
+	var buf strings.Builder
+	if err := bridge.writeTo(&buf); err != nil {
+		t.Fatalf("writeTo() returned error: %s", err)
+	}
+
+	if !strings.HasPrefix(buf.String(), "cf.firehose_events_total 1 ") {
+		t.Errorf("writeTo() = %q, want a line starting with %q", buf.String(), "cf.firehose_events_total 1 ")
+	}
+}
+
+type stubGatherer struct {
+	families []*dto.MetricFamily
+}
+
+func (g stubGatherer) Gather() ([]*dto.MetricFamily, error) {
+	return g.families, nil
+}
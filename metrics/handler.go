@@ -0,0 +1,53 @@
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// WithOpenMetrics enables content-type negotiation for
+// application/openmetrics-text on the Registry's HTTP handler, which is
+// required for exemplar support and richer metric typing.
+func WithOpenMetrics(enable bool) RegistryOption {
+	return func(r *Registry) {
+		r.handlerOpts.EnableOpenMetrics = enable
+	}
+}
+
+// WithMaxRequestsInFlight limits how many concurrent scrapes the
+// Registry's HTTP handler will serve, so that scraping under load
+// degrades gracefully instead of piling up goroutines.
+func WithMaxRequestsInFlight(n int) RegistryOption {
+	return func(r *Registry) {
+		r.handlerOpts.MaxRequestsInFlight = n
+	}
+}
+
+// WithTimeout bounds how long the Registry's HTTP handler will spend
+// gathering metrics for a single scrape.
+func WithTimeout(timeout time.Duration) RegistryOption {
+	return func(r *Registry) {
+		r.handlerOpts.Timeout = timeout
+	}
+}
+
+// WithErrorHandling sets the policy the Registry's HTTP handler applies
+// when a collector returns an error while gathering.
+func WithErrorHandling(policy promhttp.HandlerErrorHandling) RegistryOption {
+	return func(r *Registry) {
+		r.handlerOpts.ErrorHandling = policy
+	}
+}
+
+// Handler returns an http.Handler that serves the Registry's metrics,
+// honoring the options set via WithOpenMetrics, WithMaxRequestsInFlight,
+// WithTimeout and WithErrorHandling. The handler's own self-instrumentation
+// (promhttp_metric_handler_requests_total, in-flight gauge, etc.) is
+// registered back into the Registry.
+func (r *Registry) Handler() http.Handler {
+	opts := r.handlerOpts
+	opts.Registry = r.registerer
+	return promhttp.InstrumentMetricHandler(r.registerer, promhttp.HandlerFor(r.registry, opts))
+}
@@ -0,0 +1,20 @@
+package metrics
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHandlerRegistersSelfInstrumentation(t *testing.T) {
+	r := NewRegistry("firehose", nil)
+
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	rr := httptest.NewRecorder()
+	r.Handler().ServeHTTP(rr, req)
+
+	body := rr.Body.String()
+	if !strings.Contains(body, "promhttp_metric_handler_requests_total") {
+		t.Errorf("expected Handler() to register its own promhttp_metric_handler_requests_total self-instrumentation, got:\n%s", body)
+	}
+}
@@ -0,0 +1,80 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/push"
+	"github.com/prometheus/common/log"
+)
+
+// WithPushGateway configures the Registry to push its gathered metrics to
+// the Pushgateway at url under jobName. interval is used by RunPushLoop to
+// decide how often to push; it is ignored by Push/PushAdd, which push on
+// demand.
+//
+// As with WithGraphiteBridge, turning this into CLI flags is the exporter
+// entrypoint's job; this tree has no main package to wire them into yet.
+func WithPushGateway(url, jobName string, interval time.Duration) RegistryOption {
+	return func(r *Registry) {
+		r.pushURL = url
+		r.pushJobName = jobName
+		r.pushInterval = interval
+	}
+}
+
+// WithPushGroupingLabels sets the grouping key used when pushing to the
+// Pushgateway, e.g. to distinguish short-lived firehose_exporter runs from
+// different BOSH deployments or errand invocations.
+func WithPushGroupingLabels(labels map[string]string) RegistryOption {
+	return func(r *Registry) {
+		r.pushGroupingLabels = labels
+	}
+}
+
+func (r *Registry) pusher() *push.Pusher {
+	p := push.New(r.pushURL, r.pushJobName).Gatherer(r.registry)
+	for name, value := range r.pushGroupingLabels {
+		p = p.Grouping(name, value)
+	}
+	return p
+}
+
+// Push pushes the Registry's current metrics to the configured
+// Pushgateway, replacing any previously pushed metrics in the same
+// grouping key.
+func (r *Registry) Push(ctx context.Context) error {
+	return r.pusher().PushContext(ctx)
+}
+
+// PushAdd pushes the Registry's current metrics to the configured
+// Pushgateway, merging them into any previously pushed metrics in the
+// same grouping key rather than replacing them.
+func (r *Registry) PushAdd(ctx context.Context) error {
+	return r.pusher().AddContext(ctx)
+}
+
+// RunPushLoop calls PushAdd on the Registry's configured push interval
+// until ctx is cancelled. It is intended for short-lived firehose_exporter
+// runs (e.g. a BOSH errand) that scrape the firehose for a fixed duration
+// and push accumulated metrics instead of serving a long-lived /metrics
+// endpoint. It is a no-op if WithPushGateway was not used.
+func (r *Registry) RunPushLoop(ctx context.Context) {
+	if r.pushURL == "" || r.pushInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(r.pushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := r.PushAdd(ctx); err != nil {
+				log.Errorf("Error pushing metrics to `%s`: %s", r.pushURL, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package metrics
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRunPushLoopNoopWithoutPushGateway(t *testing.T) {
+	r := NewRegistry("firehose", nil)
+
+	done := make(chan struct{})
+	go func() {
+		r.RunPushLoop(context.Background())
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("RunPushLoop did not return immediately when WithPushGateway was not used")
+	}
+}
+
+func TestRunPushLoopStopsOnContextCancel(t *testing.T) {
+	r := NewRegistry("firehose", nil, WithPushGateway("http://127.0.0.1:0", "firehose_exporter", time.Hour))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		r.RunPushLoop(ctx)
+		close(done)
+	}()
+
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("RunPushLoop did not return after its context was cancelled")
+	}
+}
+
+func TestWithPushGroupingLabels(t *testing.T) {
+	r := NewRegistry(
+		"firehose",
+		nil,
+		WithPushGateway("http://127.0.0.1:0", "firehose_exporter", time.Minute),
+		WithPushGroupingLabels(map[string]string{"instance": "0"}),
+	)
+
+	p := r.pusher()
+	if p == nil {
+		t.Fatal("expected pusher() to build a *push.Pusher once WithPushGateway is configured")
+	}
+}
@@ -0,0 +1,235 @@
+package metrics
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/common/log"
+)
+
+// Registry wraps a *prometheus.Registry and applies a set of default tags
+// to every metric created through it, so internal firehose_exporter
+// metrics (envelope parsing counters, dropped-message accounting, etc.)
+// consistently carry labels such as `environment` without every call site
+// having to repeat them.
+type Registry struct {
+	namespace   string
+	registry    *prometheus.Registry
+	registerer  prometheus.Registerer
+	defaultTags prometheus.Labels
+
+	pushURL            string
+	pushJobName        string
+	pushInterval       time.Duration
+	pushGroupingLabels map[string]string
+
+	graphiteAddress  string
+	graphitePrefix   string
+	graphiteInterval time.Duration
+
+	handlerOpts promhttp.HandlerOpts
+}
+
+// RegistryOption configures a Registry at construction time.
+type RegistryOption func(*Registry)
+
+// MetricOption configures a single metric created through one of the
+// Registry's New* constructors, e.g. WithMetricTags or WithHelpText.
+type MetricOption func(*metricOptions)
+
+type metricOptions struct {
+	tags prometheus.Labels
+	help string
+}
+
+// WithMetricTags adds const labels to a single metric, on top of the
+// Registry's default tags.
+func WithMetricTags(tags map[string]string) MetricOption {
+	return func(o *metricOptions) {
+		for k, v := range tags {
+			o.tags[k] = v
+		}
+	}
+}
+
+// WithHelpText overrides the default help text generated for a metric.
+func WithHelpText(help string) MetricOption {
+	return func(o *metricOptions) {
+		o.help = help
+	}
+}
+
+// NewRegistry creates a Registry backed by its own *prometheus.Registry,
+// labelling every metric created through it with defaultTags.
+func NewRegistry(namespace string, defaultTags map[string]string, opts ...RegistryOption) *Registry {
+	reg := prometheus.NewRegistry()
+
+	r := &Registry{
+		namespace:   namespace,
+		registry:    reg,
+		registerer:  reg,
+		defaultTags: prometheus.Labels(defaultTags),
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	return r
+}
+
+// Registerer exposes the underlying prometheus.Registerer, e.g. to
+// register a collector that was not built through one of the New*
+// constructors below.
+func (r *Registry) Registerer() prometheus.Registerer {
+	return r.registerer
+}
+
+// Gatherer exposes the underlying prometheus.Gatherer, e.g. to serve it
+// over an HTTP `/metrics` endpoint.
+func (r *Registry) Gatherer() prometheus.Gatherer {
+	return r.registry
+}
+
+func (r *Registry) buildOptions(name string, opts []MetricOption) *metricOptions {
+	o := &metricOptions{
+		tags: prometheus.Labels{},
+		help: fmt.Sprintf("Cloud Foundry Firehose exporter %s metric.", name),
+	}
+
+	for k, v := range r.defaultTags {
+		o.tags[k] = v
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+// Counter mirrors prometheus.Counter, returned by Registry.NewCounter.
+type Counter interface {
+	prometheus.Counter
+}
+
+// NewCounter registers and returns a new Counter. If a counter with the
+// same name and labels was already registered through this Registry, the
+// existing counter is returned instead.
+func (r *Registry) NewCounter(name string, opts ...MetricOption) Counter {
+	o := r.buildOptions(name, opts)
+
+	counter := prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace:   r.namespace,
+		Name:        name,
+		Help:        o.help,
+		ConstLabels: o.tags,
+	})
+
+	if err := r.registerer.Register(counter); err != nil {
+		if are, ok := err.(prometheus.AlreadyRegisteredError); ok {
+			if existing, ok := are.ExistingCollector.(Counter); ok {
+				return existing
+			}
+		}
+		log.Errorf("Error registering counter `%s`: %s", name, err)
+	}
+
+	return counter
+}
+
+// Gauge mirrors prometheus.Gauge, returned by Registry.NewGauge.
+type Gauge interface {
+	prometheus.Gauge
+}
+
+// NewGauge registers and returns a new Gauge. If a gauge with the same
+// name and labels was already registered through this Registry, the
+// existing gauge is returned instead.
+func (r *Registry) NewGauge(name string, opts ...MetricOption) Gauge {
+	o := r.buildOptions(name, opts)
+
+	gauge := prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace:   r.namespace,
+		Name:        name,
+		Help:        o.help,
+		ConstLabels: o.tags,
+	})
+
+	if err := r.registerer.Register(gauge); err != nil {
+		if are, ok := err.(prometheus.AlreadyRegisteredError); ok {
+			if existing, ok := are.ExistingCollector.(Gauge); ok {
+				return existing
+			}
+		}
+		log.Errorf("Error registering gauge `%s`: %s", name, err)
+	}
+
+	return gauge
+}
+
+// Histogram mirrors prometheus.Histogram, returned by Registry.NewHistogram.
+type Histogram interface {
+	Observe(float64)
+}
+
+// NewHistogram registers and returns a new Histogram with the given
+// buckets. If a histogram with the same name and labels was already
+// registered through this Registry, the existing histogram is returned
+// instead.
+func (r *Registry) NewHistogram(name string, buckets []float64, opts ...MetricOption) Histogram {
+	o := r.buildOptions(name, opts)
+
+	histogram := prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace:   r.namespace,
+		Name:        name,
+		Help:        o.help,
+		ConstLabels: o.tags,
+		Buckets:     buckets,
+	})
+
+	if err := r.registerer.Register(histogram); err != nil {
+		if are, ok := err.(prometheus.AlreadyRegisteredError); ok {
+			if existing, ok := are.ExistingCollector.(Histogram); ok {
+				return existing
+			}
+		}
+		log.Errorf("Error registering histogram `%s`: %s", name, err)
+	}
+
+	return histogram
+}
+
+// Summary mirrors prometheus.Summary, returned by Registry.NewSummary.
+type Summary interface {
+	Observe(float64)
+}
+
+// NewSummary registers and returns a new Summary with the given
+// quantile objectives. If a summary with the same name and labels was
+// already registered through this Registry, the existing summary is
+// returned instead.
+func (r *Registry) NewSummary(name string, objectives map[float64]float64, opts ...MetricOption) Summary {
+	o := r.buildOptions(name, opts)
+
+	summary := prometheus.NewSummary(prometheus.SummaryOpts{
+		Namespace:   r.namespace,
+		Name:        name,
+		Help:        o.help,
+		ConstLabels: o.tags,
+		Objectives:  objectives,
+	})
+
+	if err := r.registerer.Register(summary); err != nil {
+		if are, ok := err.(prometheus.AlreadyRegisteredError); ok {
+			if existing, ok := are.ExistingCollector.(Summary); ok {
+				return existing
+			}
+		}
+		log.Errorf("Error registering summary `%s`: %s", name, err)
+	}
+
+	return summary
+}
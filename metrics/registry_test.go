@@ -0,0 +1,72 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestNewCounterAppliesDefaultTags(t *testing.T) {
+	r := NewRegistry("firehose", map[string]string{"environment": "test"})
+
+	r.NewCounter("events_total").Add(3)
+
+	expected := `
+# HELP firehose_events_total Cloud Foundry Firehose exporter events_total metric.
+# TYPE firehose_events_total counter
+firehose_events_total{environment="test"} 3
+`
+	if err := testutil.GatherAndCompare(r.Gatherer(), strings.NewReader(expected), "firehose_events_total"); err != nil {
+		t.Errorf("unexpected collected metrics: %s", err)
+	}
+}
+
+func TestNewCounterReturnsExistingOnDuplicateRegistration(t *testing.T) {
+	r := NewRegistry("firehose", nil)
+
+	first := r.NewCounter("events_total")
+	second := r.NewCounter("events_total")
+
+	first.Add(1)
+	second.Add(1)
+
+	expected := `
+# HELP firehose_events_total Cloud Foundry Firehose exporter events_total metric.
+# TYPE firehose_events_total counter
+firehose_events_total 2
+`
+	if err := testutil.GatherAndCompare(r.Gatherer(), strings.NewReader(expected), "firehose_events_total"); err != nil {
+		t.Errorf("expected the second NewCounter call to return the already-registered counter: %s", err)
+	}
+}
+
+func TestWithHelpTextOverridesDefaultHelp(t *testing.T) {
+	r := NewRegistry("firehose", nil)
+
+	r.NewGauge("instances", WithHelpText("Number of running instances."))
+
+	expected := `
+# HELP firehose_instances Number of running instances.
+# TYPE firehose_instances gauge
+firehose_instances 0
+`
+	if err := testutil.GatherAndCompare(r.Gatherer(), strings.NewReader(expected), "firehose_instances"); err != nil {
+		t.Errorf("unexpected collected metrics: %s", err)
+	}
+}
+
+func TestWithMetricTagsMergesOverDefaultTags(t *testing.T) {
+	r := NewRegistry("firehose", map[string]string{"environment": "test"})
+
+	r.NewGauge("instances", WithMetricTags(map[string]string{"job": "router"}))
+
+	expected := `
+# HELP firehose_instances Cloud Foundry Firehose exporter instances metric.
+# TYPE firehose_instances gauge
+firehose_instances{environment="test",job="router"} 0
+`
+	if err := testutil.GatherAndCompare(r.Gatherer(), strings.NewReader(expected), "firehose_instances"); err != nil {
+		t.Errorf("unexpected collected metrics: %s", err)
+	}
+}
@@ -0,0 +1,79 @@
+package metrics
+
+import "sync"
+
+// HttpStartStop is a single Cloud Foundry Firehose HttpStartStop event,
+// as consumed by collectors.HttpStartStopCollector.
+type HttpStartStop struct {
+	ApplicationId  string
+	InstanceId     string
+	Uri            string
+	Method         string
+	StatusCode     int32
+	ContentLength  int64
+	ClientDuration int64
+	ServerDuration int64
+}
+
+// HttpStartStops is a batch of HttpStartStop events.
+type HttpStartStops []*HttpStartStop
+
+// ValueMetric is a single Cloud Foundry Firehose ValueMetric event, as
+// consumed by collectors.ValueMetricsCollector.
+type ValueMetric struct {
+	Origin     string
+	Name       string
+	Value      float64
+	Unit       string
+	Deployment string
+	Job        string
+	Index      string
+	IP         string
+	Tags       map[string]string
+}
+
+// ValueMetrics is a batch of ValueMetric events.
+type ValueMetrics []*ValueMetric
+
+// Store accumulates every Firehose event recorded through it, in arrival
+// order, for collectors to read on each Prometheus scrape. Events are not
+// keyed by origin or application, and older events are never replaced or
+// removed.
+type Store struct {
+	mu             sync.Mutex
+	httpStartStops HttpStartStops
+	valueMetrics   ValueMetrics
+}
+
+// NewStore creates an empty Store.
+func NewStore() *Store {
+	return &Store{}
+}
+
+// SetHttpStartStop records an HttpStartStop event.
+func (s *Store) SetHttpStartStop(httpStartStop *HttpStartStop) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.httpStartStops = append(s.httpStartStops, httpStartStop)
+}
+
+// GetHttpStartStops returns the HttpStartStop events currently cached.
+func (s *Store) GetHttpStartStops() HttpStartStops {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.httpStartStops
+}
+
+// SetValueMetric records a ValueMetric event.
+func (s *Store) SetValueMetric(valueMetric *ValueMetric) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.valueMetrics = append(s.valueMetrics, valueMetric)
+}
+
+// GetValueMetrics returns the ValueMetric events currently cached.
+func (s *Store) GetValueMetrics() ValueMetrics {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.valueMetrics
+}
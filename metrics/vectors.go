@@ -0,0 +1,119 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+)
+
+// CounterVec mirrors *prometheus.CounterVec, returned by Registry.NewCounterVec.
+type CounterVec struct {
+	vec *prometheus.CounterVec
+}
+
+// With returns the Counter for the given label values, creating it if
+// necessary, mirroring (*prometheus.CounterVec).With.
+func (v *CounterVec) With(labels prometheus.Labels) Counter {
+	return v.vec.With(labels)
+}
+
+// NewCounterVec registers and returns a new CounterVec partitioned by
+// labelNames. If a CounterVec with the same name and labels was already
+// registered through this Registry, the existing CounterVec is returned
+// instead.
+func (r *Registry) NewCounterVec(name string, labelNames []string, opts ...MetricOption) *CounterVec {
+	o := r.buildOptions(name, opts)
+
+	vec := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   r.namespace,
+		Name:        name,
+		Help:        o.help,
+		ConstLabels: o.tags,
+	}, labelNames)
+
+	if err := r.registerer.Register(vec); err != nil {
+		if are, ok := err.(prometheus.AlreadyRegisteredError); ok {
+			if existing, ok := are.ExistingCollector.(*prometheus.CounterVec); ok {
+				return &CounterVec{vec: existing}
+			}
+		}
+		log.Errorf("Error registering counter vec `%s`: %s", name, err)
+	}
+
+	return &CounterVec{vec: vec}
+}
+
+// GaugeVec mirrors *prometheus.GaugeVec, returned by Registry.NewGaugeVec.
+type GaugeVec struct {
+	vec *prometheus.GaugeVec
+}
+
+// With returns the Gauge for the given label values, creating it if
+// necessary, mirroring (*prometheus.GaugeVec).With.
+func (v *GaugeVec) With(labels prometheus.Labels) Gauge {
+	return v.vec.With(labels)
+}
+
+// NewGaugeVec registers and returns a new GaugeVec partitioned by
+// labelNames. If a GaugeVec with the same name and labels was already
+// registered through this Registry, the existing GaugeVec is returned
+// instead.
+func (r *Registry) NewGaugeVec(name string, labelNames []string, opts ...MetricOption) *GaugeVec {
+	o := r.buildOptions(name, opts)
+
+	vec := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace:   r.namespace,
+		Name:        name,
+		Help:        o.help,
+		ConstLabels: o.tags,
+	}, labelNames)
+
+	if err := r.registerer.Register(vec); err != nil {
+		if are, ok := err.(prometheus.AlreadyRegisteredError); ok {
+			if existing, ok := are.ExistingCollector.(*prometheus.GaugeVec); ok {
+				return &GaugeVec{vec: existing}
+			}
+		}
+		log.Errorf("Error registering gauge vec `%s`: %s", name, err)
+	}
+
+	return &GaugeVec{vec: vec}
+}
+
+// HistogramVec mirrors *prometheus.HistogramVec, returned by
+// Registry.NewHistogramVec.
+type HistogramVec struct {
+	vec *prometheus.HistogramVec
+}
+
+// With returns the Histogram for the given label values, creating it if
+// necessary, mirroring (*prometheus.HistogramVec).With.
+func (v *HistogramVec) With(labels prometheus.Labels) Histogram {
+	return v.vec.With(labels)
+}
+
+// NewHistogramVec registers and returns a new HistogramVec partitioned by
+// labelNames. If a HistogramVec with the same name and labels was already
+// registered through this Registry, the existing HistogramVec is returned
+// instead.
+func (r *Registry) NewHistogramVec(name string, buckets []float64, labelNames []string, opts ...MetricOption) *HistogramVec {
+	o := r.buildOptions(name, opts)
+
+	vec := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace:   r.namespace,
+		Name:        name,
+		Help:        o.help,
+		ConstLabels: o.tags,
+		Buckets:     buckets,
+	}, labelNames)
+
+	if err := r.registerer.Register(vec); err != nil {
+		if are, ok := err.(prometheus.AlreadyRegisteredError); ok {
+			if existing, ok := are.ExistingCollector.(*prometheus.HistogramVec); ok {
+				return &HistogramVec{vec: existing}
+			}
+		}
+		log.Errorf("Error registering histogram vec `%s`: %s", name, err)
+	}
+
+	return &HistogramVec{vec: vec}
+}
@@ -0,0 +1,86 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestNewHistogramObservesIntoConfiguredBuckets(t *testing.T) {
+	r := NewRegistry("firehose", nil)
+
+	h := r.NewHistogram("request_duration_seconds", []float64{0.1, 1})
+	h.Observe(0.5)
+
+	expected := `
+# HELP firehose_request_duration_seconds Cloud Foundry Firehose exporter request_duration_seconds metric.
+# TYPE firehose_request_duration_seconds histogram
+firehose_request_duration_seconds_bucket{le="0.1"} 0
+firehose_request_duration_seconds_bucket{le="1"} 1
+firehose_request_duration_seconds_bucket{le="+Inf"} 1
+firehose_request_duration_seconds_sum 0.5
+firehose_request_duration_seconds_count 1
+`
+	if err := testutil.GatherAndCompare(r.Gatherer(), strings.NewReader(expected), "firehose_request_duration_seconds"); err != nil {
+		t.Errorf("unexpected collected metrics: %s", err)
+	}
+}
+
+func TestNewSummaryReturnsExistingOnDuplicateRegistration(t *testing.T) {
+	r := NewRegistry("firehose", nil)
+
+	first := r.NewSummary("request_duration_seconds", map[float64]float64{0.5: 0.05})
+	second := r.NewSummary("request_duration_seconds", map[float64]float64{0.5: 0.05})
+
+	first.Observe(1)
+	second.Observe(1)
+
+	expected := `
+# HELP firehose_request_duration_seconds Cloud Foundry Firehose exporter request_duration_seconds metric.
+# TYPE firehose_request_duration_seconds summary
+firehose_request_duration_seconds{quantile="0.5"} 1
+firehose_request_duration_seconds_sum 2
+firehose_request_duration_seconds_count 2
+`
+	if err := testutil.GatherAndCompare(r.Gatherer(), strings.NewReader(expected), "firehose_request_duration_seconds"); err != nil {
+		t.Errorf("expected the second NewSummary call to return the already-registered summary: %s", err)
+	}
+}
+
+func TestCounterVecWithCreatesPerLabelCounters(t *testing.T) {
+	r := NewRegistry("firehose", nil)
+
+	vec := r.NewCounterVec("events_total", []string{"origin"})
+	vec.With(map[string]string{"origin": "router"}).Add(2)
+	vec.With(map[string]string{"origin": "gorouter"}).Add(1)
+
+	expected := `
+# HELP firehose_events_total Cloud Foundry Firehose exporter events_total metric.
+# TYPE firehose_events_total counter
+firehose_events_total{origin="gorouter"} 1
+firehose_events_total{origin="router"} 2
+`
+	if err := testutil.GatherAndCompare(r.Gatherer(), strings.NewReader(expected), "firehose_events_total"); err != nil {
+		t.Errorf("unexpected collected metrics: %s", err)
+	}
+}
+
+func TestHistogramVecWith(t *testing.T) {
+	r := NewRegistry("firehose", nil)
+
+	vec := r.NewHistogramVec("duration_seconds", []float64{1}, []string{"method"})
+	vec.With(map[string]string{"method": "GET"}).Observe(0.5)
+
+	expected := `
+# HELP firehose_duration_seconds Cloud Foundry Firehose exporter duration_seconds metric.
+# TYPE firehose_duration_seconds histogram
+firehose_duration_seconds_bucket{method="GET",le="1"} 1
+firehose_duration_seconds_bucket{method="GET",le="+Inf"} 1
+firehose_duration_seconds_sum{method="GET"} 0.5
+firehose_duration_seconds_count{method="GET"} 1
+`
+	if err := testutil.GatherAndCompare(r.Gatherer(), strings.NewReader(expected), "firehose_duration_seconds"); err != nil {
+		t.Errorf("unexpected collected metrics: %s", err)
+	}
+}